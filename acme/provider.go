@@ -0,0 +1,52 @@
+// Package acme implements go-acme/lego's challenge.Provider on top of the
+// gandi package, so anyone already running gandyn against a Gandi zone can
+// obtain Let's Encrypt certificates for the same records via DNS-01 without
+// pulling in lego's full provider tree.
+package acme
+
+import (
+	"fmt"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+
+	"github.com/thechriswalker/gandyn/gandi"
+)
+
+// Provider implements challenge.Provider against a single Gandi LiveDNS zone.
+type Provider struct {
+	client *gandi.Client
+	ttl    uint
+}
+
+// NewProvider builds a Provider that authenticates to Gandi's LiveDNS API
+// with the legacy apiKey and manages records in zone.
+func NewProvider(apiKey, zone string) *Provider {
+	return &Provider{client: &gandi.Client{Key: apiKey, Zone: zone}, ttl: 300}
+}
+
+// NewProviderWithToken is like NewProvider, but authenticates with a Gandi
+// Personal Access Token against the v5 LiveDNS API instead of a legacy key.
+func NewProviderWithToken(token, zone string) *Provider {
+	return &Provider{client: &gandi.Client{Token: token, Zone: zone}, ttl: 300}
+}
+
+// Present creates the _acme-challenge TXT record needed to fulfil a dns-01
+// challenge for domain.
+func (p *Provider) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	name := dns01.UnFqdn(fqdn)
+	if err := p.client.CreateTXT(name, value, p.ttl); err != nil {
+		return fmt.Errorf("gandyn/acme: present %s: %w", name, err)
+	}
+	return nil
+}
+
+// CleanUp removes the TXT record created by Present.
+func (p *Provider) CleanUp(domain, token, keyAuth string) error {
+	fqdn, _ := dns01.GetRecord(domain, keyAuth)
+	name := dns01.UnFqdn(fqdn)
+	if err := p.client.DeleteTXT(name); err != nil {
+		return fmt.Errorf("gandyn/acme: cleanup %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const cloudflareBaseURL = "https://api.cloudflare.com/client/v4"
+
+// cloudflareRegistrar implements Registrar against the Cloudflare DNS API.
+type cloudflareRegistrar struct {
+	Token  string
+	ZoneID string
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+type cloudflareListResponse struct {
+	Success bool               `json:"success"`
+	Result  []cloudflareRecord `json:"result"`
+}
+
+type cloudflareStatusResponse struct {
+	Success bool `json:"success"`
+}
+
+func (c *cloudflareRegistrar) req(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, cloudflareBaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+	return http.DefaultClient.Do(req)
+}
+
+func (c *cloudflareRegistrar) find(name, rtype string) (*cloudflareRecord, error) {
+	res, err := c.req("GET", fmt.Sprintf("/zones/%s/dns_records?type=%s&name=%s", c.ZoneID, rtype, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	out := &cloudflareListResponse{}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return nil, err
+	}
+	if !out.Success || len(out.Result) == 0 {
+		return nil, errors.New("cloudflare: record not found")
+	}
+	return &out.Result[0], nil
+}
+
+// Get gets the current value of the record
+func (c *cloudflareRegistrar) Get(name, rtype string) (string, error) {
+	record, err := c.find(name, rtype)
+	if err != nil {
+		return "", err
+	}
+	return record.Content, nil
+}
+
+// Set sets the value of the record
+func (c *cloudflareRegistrar) Set(name, rtype, ip string, ttl uint) error {
+	record, err := c.find(name, rtype)
+	if err != nil {
+		return err
+	}
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(&cloudflareRecord{Type: rtype, Name: name, Content: ip, TTL: int(ttl)}); err != nil {
+		return err
+	}
+	res, err := c.req("PUT", fmt.Sprintf("/zones/%s/dns_records/%s", c.ZoneID, record.ID), body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	out := &cloudflareStatusResponse{}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return err
+	}
+	if !out.Success {
+		return errors.New("cloudflare: update failed")
+	}
+	return nil
+}
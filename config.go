@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/thechriswalker/gandyn/gandi"
+)
+
+// ProviderAccount describes the credentials for one configured registrar,
+// referenced by label from each entry in Config.Records.
+type ProviderAccount struct {
+	Provider string `json:"provider"`
+
+	APIKey   string `json:"apikey,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+
+	PorkbunKey    string `json:"porkbunKey,omitempty"`
+	PorkbunSecret string `json:"porkbunSecret,omitempty"`
+	PorkbunDomain string `json:"porkbunDomain,omitempty"`
+
+	CloudflareToken string `json:"cloudflareToken,omitempty"`
+	CloudflareZone  string `json:"cloudflareZone,omitempty"`
+
+	RFC2136Server string `json:"rfc2136Server,omitempty"`
+	RFC2136Zone   string `json:"rfc2136Zone,omitempty"`
+	RFC2136Key    string `json:"rfc2136Key,omitempty"`
+}
+
+// registrar builds the Registrar described by this account.
+func (a *ProviderAccount) registrar() (Registrar, error) {
+	switch a.Provider {
+	case "", "gandi":
+		if (a.APIKey == "" && a.Token == "") || a.Zone == "" {
+			return nil, errors.New("gandi account requires zone, and either token or apikey")
+		}
+		return &gandiRegistrar{Client: &gandi.Client{Key: a.APIKey, Token: a.Token, Zone: a.Zone, BaseURL: a.Endpoint}}, nil
+	case "porkbun":
+		if a.PorkbunKey == "" || a.PorkbunSecret == "" || a.PorkbunDomain == "" {
+			return nil, errors.New("porkbun account requires porkbunKey, porkbunSecret and porkbunDomain")
+		}
+		return &porkbunRegistrar{APIKey: a.PorkbunKey, SecretKey: a.PorkbunSecret, Domain: a.PorkbunDomain}, nil
+	case "cloudflare":
+		if a.CloudflareToken == "" || a.CloudflareZone == "" {
+			return nil, errors.New("cloudflare account requires cloudflareToken and cloudflareZone")
+		}
+		return &cloudflareRegistrar{Token: a.CloudflareToken, ZoneID: a.CloudflareZone}, nil
+	case "rfc2136":
+		if a.RFC2136Server == "" || a.RFC2136Zone == "" {
+			return nil, errors.New("rfc2136 account requires rfc2136Server and rfc2136Zone")
+		}
+		return &rfc2136Registrar{Server: a.RFC2136Server, Zone: a.RFC2136Zone, Key: a.RFC2136Key}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", a.Provider)
+	}
+}
+
+// RecordEntry is one record to keep in sync with the machine's public IP.
+// Type lists the record types to maintain for Record, "A" and/or "AAAA";
+// it defaults to just "A" when omitted. TTL defaults to 300 seconds when
+// omitted.
+type RecordEntry struct {
+	Account string   `json:"account"`
+	Record  string   `json:"record"`
+	TTL     uint     `json:"ttl,omitempty"`
+	Type    []string `json:"type,omitempty"`
+}
+
+// Config is the shape of a -config file: a set of named provider accounts,
+// and the records to watch against them.
+type Config struct {
+	Accounts map[string]ProviderAccount `json:"accounts"`
+	Records  []RecordEntry              `json:"records"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg := &Config{}
+	if err := json.NewDecoder(f).Decode(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// watchList builds a watchedRecord for each entry in the config, resolving
+// its account label to a concrete Registrar.
+func (cfg *Config) watchList() ([]*watchedRecord, error) {
+	var list []*watchedRecord
+	for _, entry := range cfg.Records {
+		account, ok := cfg.Accounts[entry.Account]
+		if !ok {
+			return nil, fmt.Errorf("record %q references unknown account %q", entry.Record, entry.Account)
+		}
+		reg, err := account.registrar()
+		if err != nil {
+			return nil, fmt.Errorf("account %q: %v", entry.Account, err)
+		}
+
+		types := entry.Type
+		if len(types) == 0 {
+			types = []string{"A"}
+		}
+
+		ttl := entry.TTL
+		if ttl == 0 {
+			ttl = 300
+		}
+		w := &watchedRecord{Name: entry.Record, Registrar: reg, TTL: ttl}
+		for _, t := range types {
+			switch t {
+			case "A":
+				w.IPv4 = true
+			case "AAAA":
+				w.IPv6 = true
+			default:
+				return nil, fmt.Errorf("record %q: unknown type %q", entry.Record, t)
+			}
+		}
+		list = append(list, w)
+	}
+	return list, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ipSource is anything that can independently report the caller's current
+// public IP address.
+type ipSource interface {
+	Resolve() (string, error)
+}
+
+// dnsIPSource resolves the public IP by asking a specific DNS server for a
+// hostname that echoes the querying client's address, such as OpenDNS's
+// myip.opendns.com (A) or Google/Cloudflare's CH TXT equivalents. Server
+// holds a classic host[:port] for udp/tcp/tls lookups; DoHURL, if set
+// instead, holds a DoH endpoint URL and takes precedence, independent of
+// -transport. See resolve.go for the lookup itself.
+type dnsIPSource struct {
+	Hostname string
+	Server   string
+	DoHURL   string
+	Type     string // "A" (default), "AAAA" or "TXT"
+	Class    string // "IN" (default) or "CH"
+
+	mu       sync.Mutex
+	cachedIP string
+	expire   time.Time
+}
+
+// httpsIPSource resolves the public IP via an HTTPS endpoint that echoes the
+// caller's address as a plain text body, such as https://api.ipify.org.
+type httpsIPSource struct {
+	URL string
+}
+
+func (h *httpsIPSource) Resolve() (string, error) {
+	res, err := http.Get(h.URL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	stringIP := strings.TrimSpace(string(body))
+	if net.ParseIP(stringIP) == nil {
+		return "", fmt.Errorf("%s: no valid address", h.URL)
+	}
+	return stringIP, nil
+}
+
+// parseIPSource parses one entry of -consensus-sources: one of
+// "dns:<hostname>@<server>[:<type>[:<class>]]" (classic UDP/TCP/DoT lookup,
+// transport selected by -transport), "doh:<url>@<hostname>[:<type>[:<class>]]"
+// (DNS-over-HTTPS lookup against the given endpoint URL, e.g.
+// "doh:https://cloudflare-dns.com/dns-query@whoami.cloudflare:TXT:CH"), or
+// "https:<url>" (plain HTTP(S) fetch of a body that is itself the IP).
+func parseIPSource(spec string) (ipSource, error) {
+	scheme, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid source %q", spec)
+	}
+	switch scheme {
+	case "dns":
+		hostServer, rest, _ := strings.Cut(rest, ":")
+		host, server, ok := strings.Cut(hostServer, "@")
+		if !ok {
+			return nil, fmt.Errorf("invalid dns source %q, want host@server", spec)
+		}
+		qtype, qclass, _ := strings.Cut(rest, ":")
+		return &dnsIPSource{Hostname: host, Server: server, Type: qtype, Class: qclass}, nil
+	case "doh":
+		url, hostRest, ok := strings.Cut(rest, "@")
+		if !ok {
+			return nil, fmt.Errorf("invalid doh source %q, want url@host[:type[:class]]", spec)
+		}
+		host, rest, _ := strings.Cut(hostRest, ":")
+		qtype, qclass, _ := strings.Cut(rest, ":")
+		return &dnsIPSource{Hostname: host, DoHURL: url, Type: qtype, Class: qclass}, nil
+	case "https":
+		return &httpsIPSource{URL: rest}, nil
+	default:
+		return nil, fmt.Errorf("unknown source scheme %q", scheme)
+	}
+}
+
+// consensusResolver queries a pool of independent ipSources in parallel and
+// only trusts the result a strict majority of them agree on, so a single
+// lying or hijacked resolver cannot silently pin the wrong address.
+type consensusResolver struct {
+	Sources     []ipSource
+	MinFraction float64 // e.g. 0.5 requires a strict majority
+}
+
+func (c *consensusResolver) Resolve() (string, error) {
+	results := make([]string, len(c.Sources))
+	var wg sync.WaitGroup
+	for i, src := range c.Sources {
+		wg.Add(1)
+		go func(i int, src ipSource) {
+			defer wg.Done()
+			if ip, err := src.Resolve(); err == nil {
+				results[i] = ip
+			}
+		}(i, src)
+	}
+	wg.Wait()
+
+	counts := map[string]int{}
+	for _, ip := range results {
+		if ip != "" {
+			counts[ip]++
+		}
+	}
+
+	var best string
+	for ip, n := range counts {
+		if n > counts[best] {
+			best = ip
+		}
+	}
+
+	if best == "" || float64(counts[best])/float64(len(c.Sources)) <= c.MinFraction {
+		return "", fmt.Errorf("no consensus: best result %q agreed by %d/%d sources", best, counts[best], len(c.Sources))
+	}
+	return best, nil
+}
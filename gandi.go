@@ -0,0 +1,19 @@
+package main
+
+import "github.com/thechriswalker/gandyn/gandi"
+
+// gandiRegistrar implements Registrar against Gandi's LiveDNS API, via the
+// importable gandi client shared with package acme.
+type gandiRegistrar struct {
+	Client *gandi.Client
+}
+
+// Get gets the current value of the record
+func (l *gandiRegistrar) Get(name, rtype string) (string, error) {
+	return l.Client.Get(name, rtype)
+}
+
+// Set sets the value of the record
+func (l *gandiRegistrar) Set(name, rtype, ip string, ttl uint) error {
+	return l.Client.Set(name, rtype, ip, ttl)
+}
@@ -0,0 +1,145 @@
+// Package gandi is a small client for Gandi's LiveDNS API. It is shared by
+// the gandyn dyndns daemon and the ACME DNS-01 provider in package acme, so
+// neither has to duplicate the HTTP/JSON plumbing.
+package gandi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// legacyBaseURL is the zone-scoped API Gandi is deprecating in favour of
+// Token-authenticated v5BaseURL. v5BaseURL is scoped by domain name rather
+// than zone uuid, so Client.Zone is read as a domain name when Token is set.
+const (
+	legacyBaseURL = "https://dns.api.gandi.net/api/v5/zones"
+	v5BaseURL     = "https://api.gandi.net/v5/livedns"
+)
+
+type record struct {
+	Kind   string   `json:"rrset_type,omitempty"`
+	Name   string   `json:"rrset_name,omitempty"`
+	TTL    uint     `json:"rrset_ttl,omitempty"`
+	Values []string `json:"rrset_values,omitempty"`
+}
+
+// Client talks to Gandi's DNS API for a single zone, using either a
+// Personal Access Token (preferred) or a legacy X-Api-Key.
+type Client struct {
+	Key   string // legacy X-Api-Key
+	Token string // Personal Access Token, sent as "Authorization: Bearer"
+	Zone  string
+
+	// BaseURL overrides the default endpoint for whichever auth mode is
+	// active, for users behind proxies or on Gandi's staging environment.
+	BaseURL string
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	if c.Token != "" {
+		return v5BaseURL
+	}
+	return legacyBaseURL
+}
+
+func (c *Client) recordURL(name, rtype string) string {
+	if c.Token != "" {
+		return fmt.Sprintf("%s/domains/%s/records/%s/%s", c.baseURL(), c.Zone, name, rtype)
+	}
+	return fmt.Sprintf("%s/%s/records/%s/%s", c.baseURL(), c.Zone, name, rtype)
+}
+
+func (c *Client) req(method, name, rtype string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.recordURL(name, rtype), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else {
+		req.Header.Set("X-Api-Key", c.Key)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token == "" && res.StatusCode == http.StatusUnauthorized {
+		log.Println("Warning: Gandi rejected the legacy X-Api-Key request with 401; X-Api-Key is deprecated, switch to -token/GANDI_PAT for the new Personal Access Token API")
+	}
+	return res, nil
+}
+
+// Get gets the current value of name's rtype record.
+func (c *Client) Get(name, rtype string) (string, error) {
+	res, err := c.req("GET", name, rtype, nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	r := &record{}
+	if err := json.NewDecoder(res.Body).Decode(r); err != nil {
+		return "", err
+	}
+	if len(r.Values) == 0 || r.Values[0] == "" {
+		return "", errors.New("Invalid Record Response")
+	}
+	return r.Values[0], nil
+}
+
+// Set sets name's rtype record to the single value ip, with the given TTL
+// in seconds.
+func (c *Client) Set(name, rtype, ip string, ttl uint) error {
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(&record{TTL: ttl, Values: []string{ip}}); err != nil {
+		return err
+	}
+	res, err := c.req("PUT", name, rtype, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// we should get a created code
+	if res.StatusCode != http.StatusCreated {
+		return fmt.Errorf("Unexpected Response Status Code [%d]", res.StatusCode)
+	}
+	return nil
+}
+
+// Delete removes name's rtype record entirely.
+func (c *Client) Delete(name, rtype string) error {
+	res, err := c.req("DELETE", name, rtype, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected Response Status Code [%d]", res.StatusCode)
+	}
+	return nil
+}
+
+// CreateTXT sets name's TXT record to the single value given, with the given
+// TTL, replacing any TXT values already there; it is used by the ACME DNS-01
+// provider in package acme.
+func (c *Client) CreateTXT(name, value string, ttl uint) error {
+	return c.Set(name, "TXT", value, ttl)
+}
+
+// DeleteTXT removes name's TXT record, cleaning up after a completed ACME
+// DNS-01 challenge.
+func (c *Client) DeleteTXT(name string) error {
+	return c.Delete(name, "TXT")
+}
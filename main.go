@@ -1,174 +1,300 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net"
-	"net/http"
 	"os"
-	"os/exec"
+	"strings"
 	"time"
+
+	"github.com/thechriswalker/gandyn/gandi"
 )
 
 var (
-	apiKey     string
-	zoneID     string
-	recordName string
-	refresh    time.Duration
-	resolver   string
-	hostname   string
-)
+	apiKey        string
+	gandiToken    string
+	gandiEndpoint string
+	zoneID        string
+	recordName    string
+	recordTTL     uint
+	refresh       time.Duration
+	resolver      string
+	hostname      string
+	provider      string
+	configPath    string
+
+	porkbunKey    string
+	porkbunSecret string
+	porkbunDomain string
+
+	cloudflareToken string
+	cloudflareZone  string
 
-const (
-	baseURL = "https://dns.api.gandi.net/api/v5/zones"
+	rfc2136Server string
+	rfc2136Zone   string
+	rfc2136Key    string
+
+	consensusSources   string
+	consensusSourcesV6 string
+	consensusMin       float64
+	resetAfter         int
+
+	ipv4 bool
+	ipv6 bool
 )
 
 // Define and parse flags
 func init() {
-	flag.StringVar(&apiKey, "apikey", "", "Mandatory. API key to access server platform")
-	flag.StringVar(&zoneID, "zone", "", "Mandatory. Zone uuid")
+	flag.StringVar(&apiKey, "apikey", "", "Legacy API key to access server platform (provider=gandi); deprecated by Gandi in favour of -token")
+	flag.StringVar(&gandiToken, "token", os.Getenv("GANDI_PAT"), "Gandi Personal Access Token (provider=gandi); defaults to $GANDI_PAT, takes precedence over -apikey")
+	flag.StringVar(&gandiEndpoint, "endpoint", "", "Override the Gandi API base URL (provider=gandi); defaults to the v5 LiveDNS API for -token, or the legacy zone API for -apikey")
+	flag.StringVar(&zoneID, "zone", "", "Zone uuid for -apikey, or domain name for -token (provider=gandi)")
 	flag.StringVar(&recordName, "record", "", "Mandatory. Record to update")
+	flag.UintVar(&recordTTL, "ttl", 300, "TTL in seconds to set on -record when its value changes")
 	flag.DurationVar(&refresh, "refresh", 5*time.Minute, "Delay between checks for public IP address updates")
 	flag.StringVar(&resolver, "resolver", "resolver1.opendns.com", "The resolver to check use for `myip` record")
 	flag.StringVar(&hostname, "myip", "myip.opendns.com", "The hostname of the record to use to check for current IP")
-}
+	flag.StringVar(&provider, "provider", "gandi", "DNS provider backend to use: gandi, porkbun, cloudflare or rfc2136")
+	flag.StringVar(&configPath, "config", "", "Path to a JSON config file describing multiple records/accounts to watch; overrides the single-record flags below when set")
+
+	flag.StringVar(&porkbunKey, "porkbun-key", "", "Porkbun API key (provider=porkbun)")
+	flag.StringVar(&porkbunSecret, "porkbun-secret", "", "Porkbun API secret key (provider=porkbun)")
+	flag.StringVar(&porkbunDomain, "porkbun-domain", "", "Porkbun root domain (provider=porkbun)")
 
-type publicIPResolver struct {
-	Hostname string
-	Server   string
+	flag.StringVar(&cloudflareToken, "cloudflare-token", "", "Cloudflare API token (provider=cloudflare)")
+	flag.StringVar(&cloudflareZone, "cloudflare-zone", "", "Cloudflare zone id (provider=cloudflare)")
+
+	flag.StringVar(&rfc2136Server, "rfc2136-server", "", "Authoritative nameserver to update (provider=rfc2136)")
+	flag.StringVar(&rfc2136Zone, "rfc2136-zone", "", "Zone to update (provider=rfc2136)")
+	flag.StringVar(&rfc2136Key, "rfc2136-key", "", "TSIG key as name:secret, passed to nsupdate -y (provider=rfc2136)")
+
+	flag.StringVar(&consensusSources, "consensus-sources", "", "Comma-separated list of public IPv4 oracles to query in parallel, as dns:<host>@<server>[:<type>] (classic, see -transport), doh:<url>@<host>[:<type>] (DNS-over-HTTPS) or https:<url>; defaults to -myip/-resolver plus a handful of independent oracles")
+	flag.StringVar(&consensusSourcesV6, "consensus-sources-v6", "", "Like -consensus-sources, for the IPv6 address pool used when -ipv6 is set")
+	flag.Float64Var(&consensusMin, "consensus-min", 0.5, "An IP is accepted only when the fraction of consensus sources agreeing on it is strictly greater than this value")
+	flag.IntVar(&resetAfter, "consensus-reset-after", 3, "Clear cached registered values and re-query the DNS provider after this many consecutive IP resolution failures (0 disables)")
+
+	flag.BoolVar(&ipv4, "ipv4", true, "Maintain an A record for -record")
+	flag.BoolVar(&ipv6, "ipv6", false, "Maintain an AAAA record for -record")
 }
 
-// Resolve gets the current pblic IP
-func (p *publicIPResolver) Resolve() (string, error) {
-	output, err := exec.Command("dig", "+time=1", "+short", p.Hostname, "@"+p.Server).Output()
-	if err != nil {
-		return "", err
+// newPublicIPResolver builds the consensus pool used to discover the
+// machine's current public address for rtype ("A" or "AAAA"). If the
+// matching -consensus-sources flag is unset it falls back to a built-in
+// default pool for that family.
+func newPublicIPResolver(rtype string) (ipSource, error) {
+	raw := consensusSources
+	specs := []string{
+		fmt.Sprintf("dns:%s@%s", hostname, resolver),
+		"dns:whoami.cloudflare@1.1.1.1:TXT:CH",
+		"dns:o-o.myaddr.l.google.com@ns1.google.com:TXT:CH",
+		"https:https://api.ipify.org",
 	}
-	if len(output) == 0 {
-		//fail.
-		return "", errors.New("no ipv4 valid address")
+	if rtype == "AAAA" {
+		raw = consensusSourcesV6
+		specs = []string{
+			fmt.Sprintf("dns:%s@%s:AAAA", hostname, resolver),
+			"dns:o-o.myaddr.l.google.com@ns1.google.com:TXT:CH",
+			"https:https://api6.ipify.org",
+		}
 	}
-	stringIP := string(output[0 : len(output)-1]) //output has a trailing newline
-	ip := net.ParseIP(stringIP)
-	if ip == nil || ip.To4() == nil {
-		return "", errors.New("no ipv4 valid address")
+	if raw != "" {
+		specs = strings.Split(raw, ",")
 	}
-	return stringIP, nil
-}
-
-type liveDNSRecord struct {
-	Kind   string   `json:"rrset_type,omitempty"`
-	Name   string   `json:"rrset_name,omitempty"`
-	TTL    uint     `json:"rrset_ttl,omitempty"`
-	Values []string `json:"rrset_values,omitempty"`
-}
 
-type liveDNSConfig struct {
-	Key    string
-	Zone   string
-	Record string
+	var sources []ipSource
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		src, err := parseIPSource(spec)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("no -consensus-sources configured")
+	}
+	return &consensusResolver{Sources: sources, MinFraction: consensusMin}, nil
 }
 
-func (l *liveDNSConfig) req(method string, body io.Reader) (*http.Response, error) {
-	url := fmt.Sprintf("%s/%s/records/%s/A", baseURL, l.Zone, l.Record)
-	//log.Println(url)
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		return nil, err
+// newRegistrar builds the Registrar selected by -provider from the
+// credential flags for that provider.
+func newRegistrar() (Registrar, error) {
+	switch provider {
+	case "", "gandi":
+		if (apiKey == "" && gandiToken == "") || zoneID == "" {
+			return nil, errors.New("gandi provider requires -zone, and either -token (or $GANDI_PAT) or -apikey")
+		}
+		return &gandiRegistrar{Client: &gandi.Client{Key: apiKey, Token: gandiToken, Zone: zoneID, BaseURL: gandiEndpoint}}, nil
+	case "porkbun":
+		if porkbunKey == "" || porkbunSecret == "" || porkbunDomain == "" {
+			return nil, errors.New("porkbun provider requires -porkbun-key, -porkbun-secret and -porkbun-domain")
+		}
+		return &porkbunRegistrar{APIKey: porkbunKey, SecretKey: porkbunSecret, Domain: porkbunDomain}, nil
+	case "cloudflare":
+		if cloudflareToken == "" || cloudflareZone == "" {
+			return nil, errors.New("cloudflare provider requires -cloudflare-token and -cloudflare-zone")
+		}
+		return &cloudflareRegistrar{Token: cloudflareToken, ZoneID: cloudflareZone}, nil
+	case "rfc2136":
+		if rfc2136Server == "" || rfc2136Zone == "" {
+			return nil, errors.New("rfc2136 provider requires -rfc2136-server and -rfc2136-zone")
+		}
+		return &rfc2136Registrar{Server: rfc2136Server, Zone: rfc2136Zone, Key: rfc2136Key}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", provider)
 	}
+}
 
-	req.Header.Set("X-Api-Key", l.Key)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+// watchedRecord is a single record being kept in sync, tracking the last
+// value we believe is registered for each family we maintain.
+type watchedRecord struct {
+	Name      string
+	Registrar Registrar
+	TTL       uint
+	IPv4      bool
+	IPv6      bool
 
-	return http.DefaultClient.Do(req)
+	registeredV4 string
+	registeredV6 string
+	fetchedV4    bool
+	fetchedV6    bool
 }
 
-// Get gets the Current value of the record
-func (l *liveDNSConfig) Get() (string, error) {
-	res, err := l.req("GET", nil)
-	if err != nil {
-		return "", err
-	}
-	record := &liveDNSRecord{}
-	defer res.Body.Close()
-	if err := json.NewDecoder(res.Body).Decode(record); err != nil {
-		return "", err
-	}
-	if record.Values == nil || len(record.Values) == 0 || record.Values[0] == "" {
-		//log.Println(record)
-		return "", errors.New("Invalid Record Response")
+// watchListFromFlags builds the single-entry watch list for the legacy
+// flag-based invocation, used when -config is not given.
+func watchListFromFlags() ([]*watchedRecord, error) {
+	if recordName == "" {
+		return nil, errors.New("missing -record")
 	}
-	return record.Values[0], nil
-}
-
-// Set sets the value of the Record
-func (l *liveDNSConfig) Set(ip string) error {
-	body := &bytes.Buffer{}
-	err := json.NewEncoder(body).Encode(&liveDNSRecord{TTL: 300, Values: []string{ip}})
-	if err != nil {
-		return err
+	if !ipv4 && !ipv6 {
+		return nil, errors.New("at least one of -ipv4 or -ipv6 must be enabled")
 	}
-	res, err := l.req("PUT", body)
+	dyndns, err := newRegistrar()
 	if err != nil {
-		return err
-	}
-
-	// we should get a created code
-	if res.StatusCode != http.StatusCreated {
-		return fmt.Errorf("Unexpected Response Status Code [%d]", res.StatusCode)
+		return nil, err
 	}
-	return nil
+	return []*watchedRecord{{Name: recordName, Registrar: dyndns, TTL: recordTTL, IPv4: ipv4, IPv6: ipv6}}, nil
 }
 
 func main() {
 	flag.Parse()
-	if apiKey == "" || recordName == "" || zoneID == "" {
-		fmt.Println("Missing one or more command line options.")
+
+	var (
+		list []*watchedRecord
+		err  error
+	)
+	if configPath != "" {
+		var cfg *Config
+		cfg, err = loadConfig(configPath)
+		if err == nil {
+			list, err = cfg.watchList()
+		}
+	} else {
+		list, err = watchListFromFlags()
+	}
+	if err != nil {
+		fmt.Println(err)
 		flag.PrintDefaults()
 		os.Exit(2)
 	}
 
-	dyndns := &liveDNSConfig{
-		Key:    apiKey,
-		Zone:   zoneID,
-		Record: recordName,
+	var needV4, needV6 bool
+	for _, w := range list {
+		needV4 = needV4 || w.IPv4
+		needV6 = needV6 || w.IPv6
 	}
 
-	publicip := &publicIPResolver{
-		Hostname: hostname,
-		Server:   resolver,
+	var ip4resolver, ip6resolver ipSource
+	if needV4 {
+		if ip4resolver, err = newPublicIPResolver("A"); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+	if needV6 {
+		if ip6resolver, err = newPublicIPResolver("AAAA"); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
 	}
 
-	var registeredIP, currentIP string
-	var err error
+	var failuresV4, failuresV6 int
 
-	loop := func() {
-		// Get the current public address
-		currentIP, err = publicip.Resolve()
+	// resolveFamily resolves the consensus public address for rtype,
+	// clearing every watched record's cache for that family after
+	// -consensus-reset-after consecutive failures so it gets re-queried
+	// from the DNS provider once resolution recovers.
+	resolveFamily := func(src ipSource, rtype string, failures *int) (string, bool) {
+		ip, err := src.Resolve()
 		if err != nil {
-			log.Println("Error: failed to get pulic IP:", err)
-			return
+			log.Printf("Error: failed to get public %s address: %v", rtype, err)
+			*failures++
+			if resetAfter > 0 && *failures >= resetAfter {
+				log.Printf("Warning: %d consecutive %s resolution failures, clearing cached records", *failures, rtype)
+				for _, w := range list {
+					if rtype == "A" {
+						w.registeredV4, w.fetchedV4 = "", false
+					} else {
+						w.registeredV6, w.fetchedV6 = "", false
+					}
+				}
+				*failures = 0
+			}
+			return "", false
 		}
+		*failures = 0
+		return ip, true
+	}
 
-		if registeredIP == "" {
-			registeredIP, err = dyndns.Get()
+	// syncFamily brings w's rtype record in line with currentIP on every
+	// tick, consulting the registrar's Get only while fetched is false (the
+	// first tick, after a failed Get, and after resolveFamily clears the
+	// cache) to learn the registered value; every drift otherwise is
+	// detected by comparing against registered, which is kept up to date
+	// locally after each successful Set.
+	syncFamily := func(w *watchedRecord, rtype, currentIP string, registered *string, fetched *bool) {
+		if !*fetched {
+			value, err := w.Registrar.Get(w.Name, rtype)
 			if err != nil {
-				log.Println("Error: failed to to get current dyndns record:", err)
+				log.Println("Error: failed to get current", rtype, "record for", w.Name, ":", err)
 				return
 			}
+			*registered = value
+			*fetched = true
+		}
 
-			if registeredIP != currentIP {
-				if err = dyndns.Set(currentIP); err != nil {
-					log.Println("Error: updating DNS record:", err)
-					return
-				}
-				log.Print("Info: updated Gandi records with IP:", currentIP)
+		if *registered == currentIP {
+			return
+		}
+		if err := w.Registrar.Set(w.Name, rtype, currentIP, w.TTL); err != nil {
+			log.Println("Error: updating", rtype, "record", w.Name, ":", err)
+			return
+		}
+		*registered = currentIP
+		log.Print("Info: updated ", rtype, " record ", w.Name, " with IP:", currentIP)
+	}
+
+	loop := func() {
+		var currentV4, currentV6 string
+		var okV4, okV6 bool
+		if needV4 {
+			currentV4, okV4 = resolveFamily(ip4resolver, "A", &failuresV4)
+		}
+		if needV6 {
+			currentV6, okV6 = resolveFamily(ip6resolver, "AAAA", &failuresV6)
+		}
+
+		for _, w := range list {
+			if w.IPv4 && okV4 {
+				syncFamily(w, "A", currentV4, &w.registeredV4, &w.fetchedV4)
+			}
+			if w.IPv6 && okV6 {
+				syncFamily(w, "AAAA", currentV6, &w.registeredV6, &w.fetchedV6)
 			}
 		}
 	}
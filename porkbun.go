@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const porkbunBaseURL = "https://api.porkbun.com/api/json/v3"
+
+// porkbunRegistrar implements Registrar against Porkbun's DNS API.
+type porkbunRegistrar struct {
+	APIKey    string
+	SecretKey string
+	Domain    string
+}
+
+type porkbunAuth struct {
+	APIKey    string `json:"apikey"`
+	SecretKey string `json:"secretapikey"`
+}
+
+type porkbunRecord struct {
+	Content string `json:"content"`
+}
+
+type porkbunRetrieveResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Records []porkbunRecord `json:"records"`
+}
+
+type porkbunStatusResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func (p *porkbunRegistrar) post(path string, payload interface{}) (*http.Response, error) {
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(payload); err != nil {
+		return nil, err
+	}
+	return http.Post(porkbunBaseURL+path, "application/json", body)
+}
+
+// subdomain returns the label portion of name relative to Domain, which is
+// what Porkbun's By-Name-Type endpoints expect ("" for the zone apex).
+func (p *porkbunRegistrar) subdomain(name string) string {
+	suffix := "." + p.Domain
+	if name == p.Domain {
+		return ""
+	}
+	if len(name) > len(suffix) && name[len(name)-len(suffix):] == suffix {
+		return name[:len(name)-len(suffix)]
+	}
+	return name
+}
+
+// Get gets the current value of the record
+func (p *porkbunRegistrar) Get(name, rtype string) (string, error) {
+	res, err := p.post(fmt.Sprintf("/dns/retrieveByNameType/%s/%s/%s", p.Domain, rtype, p.subdomain(name)), &porkbunAuth{p.APIKey, p.SecretKey})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	out := &porkbunRetrieveResponse{}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return "", err
+	}
+	if out.Status != "SUCCESS" || len(out.Records) == 0 {
+		return "", fmt.Errorf("porkbun: %s", out.Message)
+	}
+	return out.Records[0].Content, nil
+}
+
+type porkbunEditRequest struct {
+	porkbunAuth
+	Content string `json:"content"`
+	TTL     string `json:"ttl"`
+}
+
+// Set sets the value of the record
+func (p *porkbunRegistrar) Set(name, rtype, ip string, ttl uint) error {
+	res, err := p.post(fmt.Sprintf("/dns/editByNameType/%s/%s/%s", p.Domain, rtype, p.subdomain(name)), &porkbunEditRequest{
+		porkbunAuth: porkbunAuth{p.APIKey, p.SecretKey},
+		Content:     ip,
+		TTL:         fmt.Sprintf("%d", ttl),
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	out := &porkbunStatusResponse{}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return err
+	}
+	if out.Status != "SUCCESS" {
+		return fmt.Errorf("porkbun: %s", out.Message)
+	}
+	return nil
+}
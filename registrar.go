@@ -0,0 +1,14 @@
+package main
+
+// Registrar is implemented by each supported DNS provider backend. Get
+// returns the value currently registered for name, and Set updates it to ip
+// with the given TTL in seconds. name is the record as each backend expects
+// it to identify records within its zone: Cloudflare and RFC2136 want the
+// record's full name (e.g. "home.example.com"), Gandi wants the rrset label
+// relative to its zone (e.g. "home"), and Porkbun accepts either, stripping
+// its configured root domain itself. rtype is the record type being
+// queried/updated, "A" or "AAAA".
+type Registrar interface {
+	Get(name, rtype string) (string, error)
+	Set(name, rtype, ip string, ttl uint) error
+}
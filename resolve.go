@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// transport selects how classic DNS queries issued by dnsIPSource are
+// carried; it has no effect on sources using the doh: scheme, which always
+// speak DoH to their own configured URL (see dnsIPSource.DoHURL).
+var transport string
+
+func init() {
+	flag.StringVar(&transport, "transport", "udp", "Transport for dns: sources: udp, tcp or tls (DoT); doh: sources always use DoH regardless of this flag")
+}
+
+// Resolve looks up the configured record and caches the answer until its
+// TTL expires, so repeated ticks don't requery well before the record could
+// plausibly have changed.
+func (d *dnsIPSource) Resolve() (string, error) {
+	d.mu.Lock()
+	if d.cachedIP != "" && time.Now().Before(d.expire) {
+		ip := d.cachedIP
+		d.mu.Unlock()
+		return ip, nil
+	}
+	d.mu.Unlock()
+
+	ip, ttl, err := d.query()
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(ip) == nil {
+		server := d.Server
+		if d.DoHURL != "" {
+			server = d.DoHURL
+		}
+		return "", fmt.Errorf("no valid address from %s@%s", d.Hostname, server)
+	}
+
+	d.mu.Lock()
+	d.cachedIP = ip
+	d.expire = time.Now().Add(time.Duration(ttl) * time.Second)
+	d.mu.Unlock()
+
+	return ip, nil
+}
+
+func (d *dnsIPSource) qtype() uint16 {
+	switch strings.ToUpper(d.Type) {
+	case "AAAA":
+		return dns.TypeAAAA
+	case "TXT":
+		return dns.TypeTXT
+	default:
+		return dns.TypeA
+	}
+}
+
+func (d *dnsIPSource) qclass() uint16 {
+	if strings.ToUpper(d.Class) == "CH" {
+		return dns.ClassCHAOS
+	}
+	return dns.ClassINET
+}
+
+// query runs the lookup and returns the answer plus its TTL in seconds, via
+// DoH if d.DoHURL is set, or else over the configured -transport.
+func (d *dnsIPSource) query() (string, uint32, error) {
+	if d.DoHURL != "" {
+		return d.queryDoH()
+	}
+	return d.queryClassic()
+}
+
+// queryClassic handles udp, tcp and tls (DoT) via github.com/miekg/dns.
+func (d *dnsIPSource) queryClassic() (string, uint32, error) {
+	net := transport
+	port := "53"
+	if net == "tls" {
+		net = "tcp-tls"
+		port = "853"
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(d.Hostname), d.qtype())
+	m.Question[0].Qclass = d.qclass()
+
+	c := &dns.Client{Net: net, Timeout: 2 * time.Second}
+	resp, _, err := c.Exchange(m, hostPort(d.Server, port))
+	if err != nil {
+		return "", 0, err
+	}
+	return extractAnswer(resp.Answer, d.qtype())
+}
+
+func hostPort(server, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(server, defaultPort)
+}
+
+func extractAnswer(answer []dns.RR, qtype uint16) (string, uint32, error) {
+	for _, rr := range answer {
+		switch qtype {
+		case dns.TypeA:
+			if a, ok := rr.(*dns.A); ok {
+				return a.A.String(), a.Hdr.Ttl, nil
+			}
+		case dns.TypeAAAA:
+			if a, ok := rr.(*dns.AAAA); ok {
+				return a.AAAA.String(), a.Hdr.Ttl, nil
+			}
+		case dns.TypeTXT:
+			if t, ok := rr.(*dns.TXT); ok && len(t.Txt) > 0 {
+				return strings.Trim(t.Txt[0], `"`), t.Hdr.Ttl, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("no answer for %s", dns.TypeToString[qtype])
+}
+
+// queryDoH handles sources using the doh: scheme (RFC 8484): d.DoHURL is the
+// DoH endpoint URL, e.g. https://cloudflare-dns.com/dns-query.
+func (d *dnsIPSource) queryDoH() (string, uint32, error) {
+	name, err := dnsmessage.NewName(dns.Fqdn(d.Hostname))
+	if err != nil {
+		return "", 0, err
+	}
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  dnsmessage.Type(d.qtype()),
+			Class: dnsmessage.Class(d.qclass()),
+		}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return "", 0, err
+	}
+
+	req, err := http.NewRequest("POST", d.DoHURL, bytes.NewReader(packed))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(body); err != nil {
+		return "", 0, err
+	}
+	return extractDoHAnswer(resp.Answers, d.qtype())
+}
+
+func extractDoHAnswer(answers []dnsmessage.Resource, qtype uint16) (string, uint32, error) {
+	for _, a := range answers {
+		switch qtype {
+		case dns.TypeA:
+			if body, ok := a.Body.(*dnsmessage.AResource); ok {
+				ip := net.IP(body.A[:])
+				return ip.String(), a.Header.TTL, nil
+			}
+		case dns.TypeAAAA:
+			if body, ok := a.Body.(*dnsmessage.AAAAResource); ok {
+				ip := net.IP(body.AAAA[:])
+				return ip.String(), a.Header.TTL, nil
+			}
+		case dns.TypeTXT:
+			if body, ok := a.Body.(*dnsmessage.TXTResource); ok && len(body.TXT) > 0 {
+				return strings.Trim(body.TXT[0], `"`), a.Header.TTL, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("no answer for %s", dns.TypeToString[qtype])
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+)
+
+// rfc2136Registrar implements Registrar using RFC 2136 dynamic DNS updates
+// via the nsupdate tool, for any server that supports them (e.g. BIND).
+type rfc2136Registrar struct {
+	Server string
+	Zone   string
+	Key    string // TSIG key as "name:secret", passed to nsupdate -y
+}
+
+func (r *rfc2136Registrar) run(script string) error {
+	args := []string{}
+	if r.Key != "" {
+		args = append(args, "-y", r.Key)
+	}
+	cmd := exec.Command("nsupdate", args...)
+	cmd.Stdin = bytes.NewBufferString(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nsupdate: %v: %s", err, out)
+	}
+	return nil
+}
+
+// Get gets the current value of the record by querying the authoritative
+// server directly, since RFC 2136 itself has no query operation.
+func (r *rfc2136Registrar) Get(name, rtype string) (string, error) {
+	src := &dnsIPSource{Hostname: name, Server: r.Server, Type: rtype}
+	return src.Resolve()
+}
+
+// Set sets the value of the record using an RFC 2136 dynamic update. ip is
+// interpolated straight into the nsupdate script, so it is validated as a
+// real address first to rule out command injection via a malicious value.
+func (r *rfc2136Registrar) Set(name, rtype, ip string, ttl uint) error {
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("rfc2136: refusing to set %s record %s to invalid address %q", rtype, name, ip)
+	}
+	script := fmt.Sprintf("server %s\nzone %s\nupdate delete %s %s\nupdate add %s %d %s %s\nsend\n",
+		r.Server, r.Zone, name, rtype, name, ttl, rtype, ip)
+	return r.run(script)
+}